@@ -1,17 +1,27 @@
 #!/usr/bin/env bash
 # ClusterKit health check — validates Gateway, HTTPRoutes, DNS, SSL, and ExternalDNS.
 # Usage: ./scripts/health-check.sh [--verbose]
+# Set CERT_WARN_DAYS to change the certificate expiry warning threshold (default: 30).
+# Set STATIC_IP_NAME to override the reserved IP name read from the Gateway's spec.addresses.
+# Colors are disabled when stdout is not a terminal or NO_COLOR is set.
 set -euo pipefail
 
 GATEWAY_NAME="clusterkit-gateway"
 GATEWAY_NS="clusterkit"
+STATIC_IP_NAME="${STATIC_IP_NAME:-}"
 VERBOSE="${1:-}"
-RED='\033[0;31m'; GREEN='\033[0;32m'; YELLOW='\033[0;33m'; NC='\033[0m'
+CERT_WARN_DAYS="${CERT_WARN_DAYS:-30}"
+[[ "$CERT_WARN_DAYS" =~ ^[0-9]+$ ]] || { echo "CERT_WARN_DAYS must be an integer (got: $CERT_WARN_DAYS)" >&2; exit 2; }
+if [ -t 1 ] && [ -z "${NO_COLOR:-}" ]; then
+  RED='\033[0;31m'; GREEN='\033[0;32m'; YELLOW='\033[0;33m'; NC='\033[0m'
+else
+  RED=''; GREEN=''; YELLOW=''; NC=''
+fi
 PASS=0; WARN=0; FAIL=0
 
-pass()  { ((PASS++)); echo -e "  ${GREEN}OK${NC}   $1"; }
-warn()  { ((WARN++)); echo -e "  ${YELLOW}WARN${NC} $1"; }
-fail()  { ((FAIL++)); echo -e "  ${RED}FAIL${NC} $1"; }
+pass()  { PASS=$((PASS+1)); echo -e "  ${GREEN}OK${NC}   $1"; }
+warn()  { WARN=$((WARN+1)); echo -e "  ${YELLOW}WARN${NC} $1"; }
+fail()  { FAIL=$((FAIL+1)); echo -e "  ${RED}FAIL${NC} $1"; }
 
 echo "=== ClusterKit Health Check ==="
 echo ""
@@ -40,6 +50,19 @@ else
   if [ "$PROGRAMMED" = "True" ]; then pass "Gateway PROGRAMMED: True"; else fail "Gateway PROGRAMMED: $PROGRAMMED"; fi
   if [ "$ACCEPTED" = "True" ]; then pass "Gateway ACCEPTED: True"; else fail "Gateway ACCEPTED: $ACCEPTED"; fi
   if [ "$ADDRESS" != "none" ] && [ -n "$ADDRESS" ]; then pass "Gateway IP: $ADDRESS"; else fail "Gateway has no IP address"; fi
+  IP_NAME="${STATIC_IP_NAME:-$(echo "$GW_JSON" | jq -r '.spec.addresses[]? | select(.type=="NamedAddress") | .value' 2>/dev/null | head -1)}"
+  if [ -z "$IP_NAME" ]; then
+    warn "Gateway requests no NamedAddress (set STATIC_IP_NAME to verify against a reserved IP)"
+  else
+    RESERVED_IP=$(gcloud compute addresses describe "$IP_NAME" --global --format='value(address)' 2>/dev/null || echo "")
+    if [ -z "$RESERVED_IP" ]; then
+      warn "Could not look up static IP $IP_NAME (run: gcloud compute addresses describe $IP_NAME --global)"
+    elif [ "$ADDRESS" = "$RESERVED_IP" ]; then
+      pass "Gateway IP matches static IP $IP_NAME"
+    elif [ "$ADDRESS" != "none" ] && [ -n "$ADDRESS" ]; then
+      fail "Gateway IP $ADDRESS does not match static IP $IP_NAME ($RESERVED_IP) (run: kubectl describe gateway $GATEWAY_NAME -n $GATEWAY_NS; see \"Static IP already in use\" in docs/maintenance.md#gateway-troubleshooting)"
+    fi
+  fi
   if [ "$ATTACHED" -gt 0 ] 2>/dev/null; then pass "Attached routes: $ATTACHED"; else warn "No attached routes"; fi
 fi
 
@@ -82,15 +105,29 @@ fi
 # 5. SSL certificates
 echo ""
 echo "--- SSL Certificates ---"
-CERTS=$(gcloud compute ssl-certificates list --format='value(name,type,expireTime)' 2>/dev/null || echo "")
+CERTS=$(gcloud compute ssl-certificates list --format='value[separator="|"](name,type,expireTime.date("%s"),subjectAlternativeNames.list())' 2>/dev/null || echo "")
 if [ -n "$CERTS" ]; then
   CERT_COUNT=$(echo "$CERTS" | wc -l | xargs)
   pass "$CERT_COUNT SSL certificate(s)"
-  if [ "$VERBOSE" = "--verbose" ]; then
-    echo "$CERTS" | while read -r name type expire; do
-      echo "    $name ($type, expires: ${expire:-N/A})"
-    done
-  fi
+  NOW=$(date +%s)
+  while IFS='|' read -r name type expire sans; do
+    if [ -z "$expire" ]; then
+      warn "  $name ($type) has no expiry time (not yet provisioned?) [${sans:-no SANs}]"
+      continue
+    fi
+    if ! [[ "$expire" =~ ^[0-9]+$ ]]; then
+      warn "  $name ($type) has unparseable expiry time: $expire [${sans:-no SANs}]"
+      continue
+    fi
+    DAYS_LEFT=$(( (expire - NOW) / 86400 ))
+    if [ "$expire" -le "$NOW" ]; then
+      fail "  $name ($type) expired [${sans:-no SANs}]"
+    elif [ "$DAYS_LEFT" -lt "$CERT_WARN_DAYS" ]; then
+      warn "  $name ($type) expires in $DAYS_LEFT day(s) [${sans:-no SANs}]"
+    else
+      pass "  $name ($type) expires in $DAYS_LEFT days [${sans:-no SANs}]"
+    fi
+  done <<< "$CERTS"
 else
   fail "No SSL certificates found"
 fi